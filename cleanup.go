@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+// managedCommentBase 是本工具创建的所有 DNS 记录都会携带的标记前缀。
+// Cloudflare 的 Comment 过滤器只支持精确匹配（而非包含匹配），
+// 所以 Comment 字段本身必须恒等于 managedComment() 的返回值才能被 listManagedRecords 检索到；
+// 用户在配置中指定的自定义 comment 改为写入 Tags，不与这里混用
+const managedCommentBase = "managed-by=cloudflareddns"
+
+// managedComment 返回本次部署实际使用的托管标记。
+// 同一个 Cloudflare 账号下常常会运行多个互不相关的部署实例（例如管理不同客户的域名），
+// 若所有实例共用同一个固定标记，一个实例的启动清理/退出清理会把其它实例创建的记录
+// 一并误删；通过 INSTANCE_TAG 为每个部署指定独立标识后，清理逻辑就只会匹配
+// 自己创建的记录，不再需要用 FQDN/ZoneID 做额外的范围限制
+func managedComment() string {
+	if instanceTag == "" {
+		return managedCommentBase
+	}
+	return managedCommentBase + ":" + instanceTag
+}
+
+// recordTags 将用户在配置中指定的自定义 comment 转换为记录的 Tags，custom 为空时返回 nil
+func recordTags(custom string) []string {
+	if custom == "" {
+		return nil
+	}
+	return []string{custom}
+}
+
+// uniqueZoneIDs 返回 specs 涉及到的去重后的 ZoneID 列表
+func uniqueZoneIDs(specs []DomainSpec) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, s := range specs {
+		if s.ZoneID == "" || seen[s.ZoneID] {
+			continue
+		}
+		seen[s.ZoneID] = true
+		ids = append(ids, s.ZoneID)
+	}
+	return ids
+}
+
+// listManagedRecords 列出 zone 中所有携带 managedComment() 标记的 DNS 记录
+// Comment 是精确匹配过滤，因此要求被管理的记录的 Comment 字段恒等于 managedComment()
+func listManagedRecords(api *cloudflare.API, zoneID string) ([]cloudflare.DNSRecord, error) {
+	params := cloudflare.ListDNSRecordsParams{Comment: managedComment()}
+	rc := &cloudflare.ResourceContainer{Identifier: zoneID}
+	records, _, err := api.ListDNSRecords(context.Background(), rc, params)
+	return records, err
+}
+
+// deleteDNSRecord 删除 zone 下指定的 DNS 记录
+func deleteDNSRecord(api *cloudflare.API, zoneID, recordID string) error {
+	rc := &cloudflare.ResourceContainer{Identifier: zoneID}
+	return api.DeleteDNSRecord(context.Background(), rc, recordID)
+}
+
+// wantedRecordKeys 返回 specs 中每条记录对应的 "zoneID|fqdn|recordType" 集合，
+// 即当前配置下"应当存在"的托管记录，cleanupStaleRecords 据此判断哪些托管记录已过期
+func wantedRecordKeys(specs []DomainSpec) map[string]bool {
+	wanted := map[string]bool{}
+	for _, s := range specs {
+		for _, t := range s.Types {
+			wanted[s.ZoneID+"|"+s.FQDN+"|"+t] = true
+		}
+	}
+	return wanted
+}
+
+// cleanupStaleRecords 删除托管记录中已不再出现于当前 specs 配置里的过期记录
+// 这样从配置里移除一个域名后，旧记录不会一直遗留在 Cloudflare 里。
+// 扫描范围是账号下的全部 zones（而不是仅当前 specs 涉及到的 zones），
+// 否则一个域名被整段从配置中删除、且它所在的 zone 不再被任何其它域名引用时，
+// 那个 zone 就永远不会被扫到，其托管记录也就永远清理不掉
+func cleanupStaleRecords(api *cloudflare.API, zones []cloudflare.Zone, specs []DomainSpec) {
+	wanted := wantedRecordKeys(specs)
+	for _, zone := range zones {
+		records, err := listManagedRecords(api, zone.ID)
+		if err != nil {
+			fmt.Println("获取托管记录失败，跳过过期清理:", err)
+			continue
+		}
+		for _, r := range records {
+			if wanted[zone.ID+"|"+r.Name+"|"+r.Type] {
+				continue
+			}
+			if err := deleteDNSRecord(api, zone.ID, r.ID); err != nil {
+				fmt.Printf("删除过期托管记录失败(%s, %s): %s\n", r.Name, r.Type, err)
+				continue
+			}
+			fmt.Println("已删除不再配置中的过期托管记录:", r.Name, r.Type)
+		}
+	}
+}
+
+// deleteManagedRecords 删除 specs 涉及到的 zone 中所有携带托管标记的记录
+// 用于 DELETE_ON_STOP=true 时进程退出前的清理
+func deleteManagedRecords(api *cloudflare.API, specs []DomainSpec) {
+	for _, zoneID := range uniqueZoneIDs(specs) {
+		records, err := listManagedRecords(api, zoneID)
+		if err != nil {
+			fmt.Println("获取托管记录失败，跳过退出清理:", err)
+			continue
+		}
+		for _, r := range records {
+			if err := deleteDNSRecord(api, zoneID, r.ID); err != nil {
+				fmt.Printf("退出时删除托管记录失败(%s, %s): %s\n", r.Name, r.Type, err)
+				continue
+			}
+			fmt.Println("退出时已删除托管记录:", r.Name, r.Type)
+		}
+	}
+}
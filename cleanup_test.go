@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestUniqueZoneIDs(t *testing.T) {
+	specs := []DomainSpec{
+		{ZoneID: "z1"},
+		{ZoneID: "z2"},
+		{ZoneID: "z1"},
+		{ZoneID: ""},
+	}
+	got := uniqueZoneIDs(specs)
+	sort.Strings(got)
+	want := []string{"z1", "z2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueZoneIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestManagedComment(t *testing.T) {
+	orig := instanceTag
+	defer func() { instanceTag = orig }()
+
+	instanceTag = ""
+	if got := managedComment(); got != managedCommentBase {
+		t.Errorf("managedComment() with no INSTANCE_TAG = %q, want %q", got, managedCommentBase)
+	}
+
+	instanceTag = "prod"
+	want := managedCommentBase + ":prod"
+	if got := managedComment(); got != want {
+		t.Errorf("managedComment() with INSTANCE_TAG=prod = %q, want %q", got, want)
+	}
+}
+
+func TestRecordTags(t *testing.T) {
+	if got := recordTags(""); got != nil {
+		t.Errorf("recordTags(\"\") = %v, want nil", got)
+	}
+	if got := recordTags("custom"); !reflect.DeepEqual(got, []string{"custom"}) {
+		t.Errorf("recordTags(\"custom\") = %v, want [custom]", got)
+	}
+}
+
+func TestWantedRecordKeys(t *testing.T) {
+	specs := []DomainSpec{
+		{ZoneID: "z1", FQDN: "a.example.com", Types: []string{recordTypeA, recordTypeAAAA}},
+		{ZoneID: "z2", FQDN: "b.example.com", Types: []string{recordTypeA}},
+	}
+	wanted := wantedRecordKeys(specs)
+
+	cases := []struct {
+		key  string
+		want bool
+	}{
+		{"z1|a.example.com|A", true},
+		{"z1|a.example.com|AAAA", true},
+		{"z2|b.example.com|A", true},
+		// 同名记录换了 zone（例如域名被改配到另一个 zone）不应被视为仍托管
+		{"z2|a.example.com|A", false},
+		// 同 zone 同名但类型未在配置中启用
+		{"z2|b.example.com|AAAA", false},
+		// 已经从配置中整段移除的域名
+		{"z1|removed.example.com|A", false},
+	}
+	for _, c := range cases {
+		if got := wanted[c.key]; got != c.want {
+			t.Errorf("wanted[%q] = %v, want %v", c.key, got, c.want)
+		}
+	}
+}
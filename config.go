@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configEntry 对应配置文件中的一条记录配置，字段含义与 ddnser 的 config.json 保持一致
+type configEntry struct {
+	Name     string `json:"name" yaml:"name"`
+	ZoneName string `json:"zoneName" yaml:"zoneName"`
+	Type     string `json:"type" yaml:"type"`
+	Proxied  *bool  `json:"proxied" yaml:"proxied"`
+	TTL      int    `json:"ttl" yaml:"ttl"`
+	Comment  string `json:"comment" yaml:"comment"`
+}
+
+// LoadConfig 从 JSON 或 YAML 配置文件加载域名列表，根据文件扩展名选择解析方式
+// （.yaml/.yml 按 YAML 解析，其余一律按 JSON 解析）
+// path 为空时返回 (nil, nil)，调用方应回退到环境变量方式
+func LoadConfig(path string) ([]DomainSpec, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	var entries []configEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
+	}
+
+	// 同名记录（例如一条 A、一条 AAAA）合并为同一个 DomainSpec，按 Types 区分记录类型；
+	// proxied/ttl/comment 是逐条记录的属性，按记录类型分别存入 ProxiedByType/TTLByType/CommentByType，
+	// 避免同名但类型不同的多条记录互相覆盖彼此的取值
+	specsByName := map[string]*DomainSpec{}
+	var order []string
+	for _, e := range entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("配置文件中存在未指定 name 的记录")
+		}
+		spec, ok := specsByName[e.Name]
+		if !ok {
+			spec = &DomainSpec{
+				FQDN:          e.Name,
+				ZoneName:      e.ZoneName,
+				Proxied:       defaultProxied,
+				TTL:           defaultTTL,
+				ProxiedByType: map[string]bool{},
+				TTLByType:     map[string]int{},
+				CommentByType: map[string]string{},
+				recordIDs:     map[string]string{},
+			}
+			specsByName[e.Name] = spec
+			order = append(order, e.Name)
+		}
+		recordType := strings.ToUpper(e.Type)
+		if recordType == "" {
+			recordType = recordTypeA
+		}
+		proxied := defaultProxied
+		if e.Proxied != nil {
+			proxied = *e.Proxied
+		}
+		ttl := defaultTTL
+		if e.TTL != 0 {
+			ttl = e.TTL
+		}
+		spec.ProxiedByType[recordType] = proxied
+		spec.TTLByType[recordType] = ttl
+		spec.CommentByType[recordType] = e.Comment
+		spec.Types = append(spec.Types, recordType)
+	}
+
+	specs := make([]DomainSpec, 0, len(order))
+	for _, name := range order {
+		specs = append(specs, *specsByName[name])
+	}
+	return specs, nil
+}
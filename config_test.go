@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigEmptyPath(t *testing.T) {
+	specs, err := LoadConfig("")
+	if err != nil || specs != nil {
+		t.Fatalf("LoadConfig(\"\") = %v, %v, want nil, nil", specs, err)
+	}
+}
+
+func TestLoadConfigMergesRecordTypesByName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	content := `[
+		{"name": "a.example.com", "zoneName": "example.com", "type": "A", "proxied": true, "ttl": 120, "comment": "a-record"},
+		{"name": "a.example.com", "type": "AAAA", "comment": "aaaa-record"}
+	]`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(specs) != 1 {
+		t.Fatalf("LoadConfig() returned %d specs, want 1 (same name should merge)", len(specs))
+	}
+
+	spec := specs[0]
+	if spec.FQDN != "a.example.com" || spec.ZoneName != "example.com" {
+		t.Errorf("spec = %+v, want FQDN/ZoneName from first entry", spec)
+	}
+	if len(spec.Types) != 2 || spec.Types[0] != recordTypeA || spec.Types[1] != recordTypeAAAA {
+		t.Errorf("spec.Types = %v, want [A AAAA]", spec.Types)
+	}
+	// A 记录按条目显式指定的值，AAAA 未指定 proxied/ttl 时应回退到包级默认值，
+	// 而不是被 A 记录的取值覆盖
+	if !spec.proxiedFor(recordTypeA) || spec.ttlFor(recordTypeA) != 120 {
+		t.Errorf("A record proxied/ttl = %v/%d, want true/120", spec.proxiedFor(recordTypeA), spec.ttlFor(recordTypeA))
+	}
+	if spec.proxiedFor(recordTypeAAAA) != defaultProxied || spec.ttlFor(recordTypeAAAA) != defaultTTL {
+		t.Errorf("AAAA record proxied/ttl = %v/%d, want defaults", spec.proxiedFor(recordTypeAAAA), spec.ttlFor(recordTypeAAAA))
+	}
+	if spec.commentFor(recordTypeA) != "a-record" || spec.commentFor(recordTypeAAAA) != "aaaa-record" {
+		t.Errorf("comments = %q/%q, want a-record/aaaa-record", spec.commentFor(recordTypeA), spec.commentFor(recordTypeAAAA))
+	}
+}
+
+func TestLoadConfigRejectsMissingName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`[{"type": "A"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig() with missing name should error, got nil")
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "- name: a.example.com\n  type: A\n  ttl: 300\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(specs) != 1 || specs[0].ttlFor(recordTypeA) != 300 {
+		t.Errorf("specs = %+v, want one spec with ttl=300", specs)
+	}
+}
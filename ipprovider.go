@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// IPProvider 用于探测当前公网 IP 地址
+type IPProvider interface {
+	// GetIP 根据记录类型（A/AAAA）返回当前公网地址
+	GetIP(recordType string) (string, error)
+}
+
+// httpJSONProvider 探测返回 JSON（形如 {"ip": "..."}）的服务，如 ipinfo.io、ipify
+type httpJSONProvider struct {
+	v4URL string
+	v6URL string
+}
+
+func (p *httpJSONProvider) GetIP(recordType string) (string, error) {
+	url := p.v4URL
+	if recordType == recordTypeAAAA {
+		url = p.v6URL
+	}
+	if url == "" {
+		return "", fmt.Errorf("当前 IP_PROVIDER 不支持探测 %s 记录", recordType)
+	}
+	return fetchIP(url, true)
+}
+
+// httpPlainProvider 探测返回纯文本 IP 的服务，如 icanhazip.com
+type httpPlainProvider struct {
+	v4URL string
+	v6URL string
+}
+
+func (p *httpPlainProvider) GetIP(recordType string) (string, error) {
+	url := p.v4URL
+	if recordType == recordTypeAAAA {
+		url = p.v6URL
+	}
+	if url == "" {
+		return "", fmt.Errorf("当前 IP_PROVIDER 不支持探测 %s 记录", recordType)
+	}
+	return fetchIP(url, false)
+}
+
+// fetchIP 请求 url 并提取其中的 IP 地址，parseJSON 为 true 时按 {"ip": "..."} 解析响应体
+func fetchIP(url string, parseJSON bool) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(string(body))
+	if parseJSON {
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err == nil {
+			if v, ok := data["ip"].(string); ok {
+				ip = v
+			}
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("未能从 %s 解析出 IP 地址", url)
+	}
+	return ip, nil
+}
+
+// cloudflareTraceProvider 通过 Cloudflare 的 /cdn-cgi/trace 获取出口 IP
+// v4URL/v6URL 分别指向 Cloudflare 的 IPv4/IPv6 字面量边缘节点，
+// 确保 AAAA 查询真正经由 IPv6 连接探测，而不是把 IPv4 出口地址误当作 IPv6 返回
+type cloudflareTraceProvider struct {
+	v4URL string
+	v6URL string
+}
+
+func (p cloudflareTraceProvider) GetIP(recordType string) (string, error) {
+	url := p.v4URL
+	if recordType == recordTypeAAAA {
+		url = p.v6URL
+	}
+	if url == "" {
+		return "", fmt.Errorf("当前 IP_PROVIDER 不支持探测 %s 记录", recordType)
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if ip, ok := strings.CutPrefix(line, "ip="); ok {
+			return strings.TrimSpace(ip), nil
+		}
+	}
+	return "", fmt.Errorf("未能在 Cloudflare trace 响应中找到 ip= 字段")
+}
+
+// interfaceProvider 读取本机指定网卡上的全局可路由地址，适用于主机本身持有公网 IP 的场景
+// skipLinkLocal/skipPrivate 均可通过 IP_PROVIDER 的 key=value 选项关闭；
+// 当网卡上有多个符合条件的地址时，preferSubnet 非空则优先选取落在该网段内的地址，
+// 否则按网卡上报的地址顺序选取第一个，结果是确定性的
+type interfaceProvider struct {
+	name          string
+	skipLinkLocal bool
+	skipPrivate   bool
+	preferSubnet  *net.IPNet
+}
+
+func (p *interfaceProvider) GetIP(recordType string) (string, error) {
+	iface, err := net.InterfaceByName(p.name)
+	if err != nil {
+		return "", fmt.Errorf("找不到网卡 %s: %w", p.name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	wantV6 := recordType == recordTypeAAAA
+	var candidates []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		isV4 := ip.To4() != nil
+		if wantV6 == isV4 {
+			continue
+		}
+		if ip.IsLoopback() {
+			continue
+		}
+		if p.skipLinkLocal && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+			continue
+		}
+		if p.skipPrivate && ip.IsPrivate() {
+			continue
+		}
+		candidates = append(candidates, ip)
+	}
+	if p.preferSubnet != nil {
+		for _, ip := range candidates {
+			if p.preferSubnet.Contains(ip) {
+				return ip.String(), nil
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0].String(), nil
+	}
+	return "", fmt.Errorf("网卡 %s 上未找到符合条件的 %s 地址", p.name, recordType)
+}
+
+// chainProvider 依次尝试多个 IPProvider，返回第一个探测成功的结果
+type chainProvider struct {
+	providers []IPProvider
+}
+
+func (c *chainProvider) GetIP(recordType string) (string, error) {
+	var lastErr error
+	for _, p := range c.providers {
+		ip, err := p.GetIP(recordType)
+		if err == nil && ip != "" {
+			return ip, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的 IP_PROVIDER")
+	}
+	return "", lastErr
+}
+
+// newIPProvider 根据一条 IP_PROVIDER 条目构造单个 IPProvider
+// 条目格式为 "name[|key=value...]"（与 DOMAINS 的写法一致），例如：
+//
+//	interface:eth0|skipPrivate=false|subnet=2001:db8::/32
+//
+// "interface:eth0" 形式用于指定需要读取地址的本地网卡
+func newIPProvider(entry string) (IPProvider, error) {
+	fields := strings.Split(entry, "|")
+	name := fields[0]
+	opts := map[string]string{}
+	for _, opt := range fields[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) == 2 {
+			opts[kv[0]] = kv[1]
+		}
+	}
+
+	if ifaceName, ok := strings.CutPrefix(name, "interface:"); ok {
+		return newInterfaceProvider(ifaceName, opts)
+	}
+	switch name {
+	case "ipinfo":
+		return &httpJSONProvider{v4URL: "https://ipinfo.io"}, nil
+	case "ipify":
+		return &httpJSONProvider{v4URL: "https://api.ipify.org?format=json", v6URL: "https://api64.ipify.org?format=json"}, nil
+	case "icanhazip":
+		return &httpPlainProvider{v4URL: "https://icanhazip.com", v6URL: "https://ipv6.icanhazip.com"}, nil
+	case "cloudflare":
+		return cloudflareTraceProvider{v4URL: "https://1.1.1.1/cdn-cgi/trace", v6URL: "https://[2606:4700:4700::1111]/cdn-cgi/trace"}, nil
+	case "":
+		return nil, fmt.Errorf("IP_PROVIDER 名称不能为空")
+	default:
+		return nil, fmt.Errorf("未知的 IP_PROVIDER: %s", name)
+	}
+}
+
+// newInterfaceProvider 根据 key=value 选项构造 interfaceProvider
+// 支持的 key 有 skipLinkLocal、skipPrivate（默认均为 true）和 subnet（CIDR，多地址匹配时优先选取）
+func newInterfaceProvider(ifaceName string, opts map[string]string) (IPProvider, error) {
+	p := &interfaceProvider{name: ifaceName, skipLinkLocal: true, skipPrivate: true}
+	if v, ok := opts["skipLinkLocal"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("interface 的 skipLinkLocal 选项不是合法的布尔值: %s", v)
+		}
+		p.skipLinkLocal = b
+	}
+	if v, ok := opts["skipPrivate"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("interface 的 skipPrivate 选项不是合法的布尔值: %s", v)
+		}
+		p.skipPrivate = b
+	}
+	if v, ok := opts["subnet"]; ok {
+		_, subnet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("interface 的 subnet 选项不是合法的 CIDR: %s", v)
+		}
+		p.preferSubnet = subnet
+	}
+	return p, nil
+}
+
+// newIPProviderChain 解析逗号分隔的 IP_PROVIDER 列表，构造依次回退的 Provider 链
+func newIPProviderChain(raw string) (IPProvider, error) {
+	entries := strings.Split(raw, ",")
+	providers := make([]IPProvider, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		p, err := newIPProvider(e)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("IP_PROVIDER 未配置有效的探测服务")
+	}
+	return &chainProvider{providers: providers}, nil
+}
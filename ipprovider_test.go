@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// stubProvider 是用于测试 chainProvider 回退逻辑的假 IPProvider，不发起真实网络请求
+type stubProvider struct {
+	ip  string
+	err error
+}
+
+func (s *stubProvider) GetIP(recordType string) (string, error) {
+	return s.ip, s.err
+}
+
+func TestNewIPProviderKnownNames(t *testing.T) {
+	for _, name := range []string{"ipinfo", "ipify", "icanhazip", "cloudflare"} {
+		p, err := newIPProvider(name)
+		if err != nil {
+			t.Errorf("newIPProvider(%q) error = %v, want nil", name, err)
+		}
+		if p == nil {
+			t.Errorf("newIPProvider(%q) = nil provider", name)
+		}
+	}
+}
+
+func TestNewIPProviderEmptyName(t *testing.T) {
+	if _, err := newIPProvider(""); err == nil {
+		t.Error("newIPProvider(\"\") should error, got nil")
+	}
+}
+
+func TestNewIPProviderUnknownName(t *testing.T) {
+	if _, err := newIPProvider("not-a-real-provider"); err == nil {
+		t.Error("newIPProvider() with unknown name should error, got nil")
+	}
+}
+
+func TestNewIPProviderInterfaceOptions(t *testing.T) {
+	p, err := newIPProvider("interface:eth0|skipPrivate=false|subnet=2001:db8::/32")
+	if err != nil {
+		t.Fatalf("newIPProvider() error = %v", err)
+	}
+	ifp, ok := p.(*interfaceProvider)
+	if !ok {
+		t.Fatalf("newIPProvider() = %T, want *interfaceProvider", p)
+	}
+	if ifp.name != "eth0" {
+		t.Errorf("ifp.name = %q, want eth0", ifp.name)
+	}
+	if ifp.skipPrivate {
+		t.Error("ifp.skipPrivate = true, want false (explicitly disabled)")
+	}
+	if !ifp.skipLinkLocal {
+		t.Error("ifp.skipLinkLocal = false, want true (default unchanged)")
+	}
+	if ifp.preferSubnet == nil || ifp.preferSubnet.String() != "2001:db8::/32" {
+		t.Errorf("ifp.preferSubnet = %v, want 2001:db8::/32", ifp.preferSubnet)
+	}
+}
+
+func TestNewIPProviderInterfaceInvalidOption(t *testing.T) {
+	if _, err := newIPProvider("interface:eth0|skipPrivate=nope"); err == nil {
+		t.Error("newIPProvider() with invalid skipPrivate should error, got nil")
+	}
+	if _, err := newIPProvider("interface:eth0|subnet=not-a-cidr"); err == nil {
+		t.Error("newIPProvider() with invalid subnet should error, got nil")
+	}
+}
+
+func TestChainProviderFallsBackOnError(t *testing.T) {
+	c := &chainProvider{providers: []IPProvider{
+		&stubProvider{err: fmt.Errorf("boom")},
+		&stubProvider{ip: "203.0.113.1"},
+	}}
+	ip, err := c.GetIP(recordTypeA)
+	if err != nil {
+		t.Fatalf("GetIP() error = %v", err)
+	}
+	if ip != "203.0.113.1" {
+		t.Errorf("GetIP() = %q, want 203.0.113.1 from second provider", ip)
+	}
+}
+
+func TestChainProviderSkipsEmptyResult(t *testing.T) {
+	c := &chainProvider{providers: []IPProvider{
+		&stubProvider{ip: ""},
+		&stubProvider{ip: "203.0.113.2"},
+	}}
+	ip, err := c.GetIP(recordTypeA)
+	if err != nil {
+		t.Fatalf("GetIP() error = %v", err)
+	}
+	if ip != "203.0.113.2" {
+		t.Errorf("GetIP() = %q, want 203.0.113.2", ip)
+	}
+}
+
+func TestChainProviderAllFail(t *testing.T) {
+	wantErr := fmt.Errorf("last failure")
+	c := &chainProvider{providers: []IPProvider{
+		&stubProvider{err: fmt.Errorf("first failure")},
+		&stubProvider{err: wantErr},
+	}}
+	_, err := c.GetIP(recordTypeA)
+	if err == nil {
+		t.Fatal("GetIP() should error when all providers fail, got nil")
+	}
+	if err.Error() != wantErr.Error() {
+		t.Errorf("GetIP() error = %v, want last provider's error %v", err, wantErr)
+	}
+}
+
+func TestNewIPProviderChain(t *testing.T) {
+	p, err := newIPProviderChain("ipinfo, ipify")
+	if err != nil {
+		t.Fatalf("newIPProviderChain() error = %v", err)
+	}
+	cp, ok := p.(*chainProvider)
+	if !ok || len(cp.providers) != 2 {
+		t.Errorf("newIPProviderChain() = %+v, want 2 chained providers", p)
+	}
+}
+
+func TestNewIPProviderChainEmpty(t *testing.T) {
+	if _, err := newIPProviderChain(""); err == nil {
+		t.Error("newIPProviderChain(\"\") should error, got nil")
+	}
+}
@@ -2,57 +2,233 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
-	"time"
+	"syscall"
 
 	"github.com/cloudflare/cloudflare-go"
 )
 
 const (
-	ipInfoAPI1 = "https://ipinfo.io"
-	ipInfoAPI2 = "https://api.ipify.org?format=json"
-)
+	recordTypeA    = "A"
+	recordTypeAAAA = "AAAA"
 
-var ipInfoAPIs = [...]string{
-	ipInfoAPI1,
-	ipInfoAPI2,
-}
+	defaultIPProvider = "ipinfo,ipify"
+)
 
 var (
-	apiToken    = os.Getenv("APITOKEN")
-	domain      = os.Getenv("DOMAIN")
-	prefix      = os.Getenv("PREFIX")
-	segment     = os.Getenv("SEGMENT")
-	period, _   = strconv.ParseUint(os.Getenv("PERIOD"), 10, 64)
-	zoneID      string
-	recordID    string
-	subDomain   string
-	fullDomain  string
-	currentZone *cloudflare.Zone
+	apiToken       = os.Getenv("APITOKEN")
+	domainsEnv     = os.Getenv("DOMAINS")
+	period, _      = strconv.ParseUint(os.Getenv("PERIOD"), 10, 64)
+	ip4Enabled     = parseBoolEnv("IP4_ENABLED", true)
+	ip6Enabled     = parseBoolEnv("IP6_ENABLED", false)
+	defaultProxied = parseBoolEnv("PROXIED", false)
+	defaultTTL, _  = strconv.Atoi(os.Getenv("TTL"))
+	ipProviderEnv  = os.Getenv("IP_PROVIDER")
+	scheduleEnv    = os.Getenv("SCHEDULE")
+	tz             = os.Getenv("TZ")
+	deleteOnStop   = parseBoolEnv("DELETE_ON_STOP", false)
+	instanceTag    = os.Getenv("INSTANCE_TAG")
 )
 
+// DomainSpec 描述一个需要维护的 DNS 记录及其所属 Zone
+// Proxied/TTL/Comment 是未按记录类型覆盖时使用的默认值；
+// ProxiedByType/TTLByType/CommentByType 允许同一 FQDN 的不同记录类型
+// （如 A 与 AAAA）各自持有独立的值，供配置文件逐条指定
+type DomainSpec struct {
+	FQDN          string
+	ZoneID        string
+	ZoneName      string // 显式指定时跳过自动匹配，直接按名称查找 Zone
+	Types         []string
+	Proxied       bool
+	TTL           int
+	Comment       string
+	ProxiedByType map[string]bool
+	TTLByType     map[string]int
+	CommentByType map[string]string
+	recordIDs     map[string]string // recordType -> recordID 缓存
+}
+
+// proxiedFor 返回 recordType 对应的 Proxied 取值，未单独设置时回退到 Proxied
+func (s *DomainSpec) proxiedFor(recordType string) bool {
+	if v, ok := s.ProxiedByType[recordType]; ok {
+		return v
+	}
+	return s.Proxied
+}
+
+// ttlFor 返回 recordType 对应的 TTL 取值，未单独设置时回退到 TTL
+func (s *DomainSpec) ttlFor(recordType string) int {
+	if v, ok := s.TTLByType[recordType]; ok {
+		return v
+	}
+	return s.TTL
+}
+
+// commentFor 返回 recordType 对应的 Comment 取值，未单独设置时回退到 Comment
+func (s *DomainSpec) commentFor(recordType string) string {
+	if v, ok := s.CommentByType[recordType]; ok {
+		return v
+	}
+	return s.Comment
+}
+
+// parseBoolEnv 读取布尔类型的环境变量，未设置时返回 defaultValue
+func parseBoolEnv(key string, defaultValue bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// parseDomains 解析 DOMAINS 环境变量，格式为 "fqdn[|key=value...],fqdn2[|...]"
+// 支持的 key 有 proxied、ttl，用于覆盖该域名的全局默认值
+func parseDomains(raw string) ([]DomainSpec, error) {
+	parts := strings.Split(raw, ",")
+	specs := make([]DomainSpec, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, "|")
+		spec := DomainSpec{
+			FQDN:    fields[0],
+			Proxied: defaultProxied,
+			TTL:     defaultTTL,
+			Types:   enabledRecordTypes(),
+		}
+		for _, opt := range fields[1:] {
+			kv := strings.SplitN(opt, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			// 解析失败时报错中止，而不是静默写入类型的零值——
+			// 例如 proxied 解析失败若悄悄回退成 false，会在用户没意识到的情况下
+			// 把一条本该走 Cloudflare 代理的记录变成直连，影响到底是否暴露源站 IP
+			switch kv[0] {
+			case "proxied":
+				v, err := strconv.ParseBool(kv[1])
+				if err != nil {
+					return nil, fmt.Errorf("DOMAINS 中 %s 的 proxied 取值非法: %s", spec.FQDN, kv[1])
+				}
+				spec.Proxied = v
+			case "ttl":
+				v, err := strconv.Atoi(kv[1])
+				if err != nil {
+					return nil, fmt.Errorf("DOMAINS 中 %s 的 ttl 取值非法: %s", spec.FQDN, kv[1])
+				}
+				spec.TTL = v
+			}
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("DOMAINS 未配置有效的域名")
+	}
+	return specs, nil
+}
+
+// enabledRecordTypes 根据 IP4_ENABLED/IP6_ENABLED 返回需要维护的记录类型
+func enabledRecordTypes() []string {
+	var types []string
+	if ip4Enabled {
+		types = append(types, recordTypeA)
+	}
+	if ip6Enabled {
+		types = append(types, recordTypeAAAA)
+	}
+	return types
+}
+
+// isWildcard 判断域名是否为泛域名记录（以 "*." 开头）
+func isWildcard(fqdn string) bool {
+	return strings.HasPrefix(fqdn, "*.")
+}
+
+// findZoneByName 按精确名称查找 Zone，用于配置文件显式指定 zoneName 的情况
+func findZoneByName(zones []cloudflare.Zone, name string) *cloudflare.Zone {
+	for i := range zones {
+		if zones[i].Name == name {
+			return &zones[i]
+		}
+	}
+	return nil
+}
+
+// resolveZone 在 zones 中找到与 fqdn 匹配、名称最长的 Zone
+// 按最长后缀匹配以正确处理 .co.uk 等多级 TLD
+func resolveZone(zones []cloudflare.Zone, fqdn string) *cloudflare.Zone {
+	var best *cloudflare.Zone
+	for i := range zones {
+		z := &zones[i]
+		if fqdn != z.Name && !strings.HasSuffix(fqdn, "."+z.Name) {
+			continue
+		}
+		if best == nil || len(z.Name) > len(best.Name) {
+			best = z
+		}
+	}
+	return best
+}
+
 func main() {
+	var configPath string
+	var once bool
+	flag.StringVar(&configPath, "config", os.Getenv("CONFIG"), "配置文件路径(JSON)，设置后优先于 DOMAINS 等环境变量")
+	flag.BoolVar(&once, "once", false, "仅执行一次更新后退出，适合配合 systemd timer 或 Kubernetes CronJob 使用")
+	flag.Parse()
+
 	// 检查环境变量
-	if apiToken == "" || domain == "" || prefix == "" {
-		fmt.Println("请设置必要的环境变量: APITOKEN, DOMAIN, PREFIX, PERIOD")
+	if apiToken == "" {
+		fmt.Println("请设置必要的环境变量: APITOKEN")
 		os.Exit(1)
 	}
 	if period == 0 {
 		period = 60
 	}
+	// 使用配置文件时，记录类型完全由每条配置的 type 字段决定，与 IP4_ENABLED/IP6_ENABLED 无关
+	if configPath == "" && !ip4Enabled && !ip6Enabled {
+		fmt.Println("IP4_ENABLED 和 IP6_ENABLED 不能同时为 false")
+		os.Exit(1)
+	}
+	if defaultTTL == 0 {
+		defaultTTL = 1 // 1 表示自动
+	}
+	if ipProviderEnv == "" {
+		ipProviderEnv = defaultIPProvider
+	}
+	ipProvider, err := newIPProviderChain(ipProviderEnv)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-	subDomain = prefix
-	if segment != "" {
-		subDomain += "." + segment
+	specs, err := LoadConfig(configPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if specs == nil {
+		if domainsEnv == "" {
+			fmt.Println("请设置必要的环境变量: DOMAINS，或通过 -config/CONFIG 指定配置文件")
+			os.Exit(1)
+		}
+		specs, err = parseDomains(domainsEnv)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
-	fullDomain = subDomain + "." + domain
-	fmt.Println("域名:", fullDomain)
 
 	// 设置 Cloudflare API 密钥
 	api, err := cloudflare.NewWithAPIToken(apiToken)
@@ -61,114 +237,126 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 获取所有 Zones，根据顶级域名进行过滤
-	zones, err := api.ListZones(context.Background(), domain)
+	// 获取账号下所有 Zones，用于为每个域名匹配所属 Zone
+	zones, err := api.ListZones(context.Background())
 	if err != nil {
 		fmt.Println("获取 Cloudflare Zones 失败:", err)
 		os.Exit(1)
 	}
-	// 寻找匹配的 Zone
-	for _, z := range zones {
-		if strings.HasSuffix(domain, z.Name) {
-			currentZone = &z
-			zoneID = z.ID
-			fmt.Println("获取ZoneID成功:", zoneID)
-			break
-		}
-	}
-	if currentZone == nil {
-		fmt.Printf("找不到与域名 %s 匹配的 Cloudflare Zone\n", domain)
-		os.Exit(1)
-	}
-	// 定期执行更新操作
-	for {
-		currentIP, err := getCurrentIP()
-		if err != nil {
-			fmt.Println("获取当前外网地址失败:", err)
-			continue
+
+	// 为每个域名解析所属 Zone，并缓存 ZoneID
+	for i := range specs {
+		var zone *cloudflare.Zone
+		if specs[i].ZoneName != "" {
+			zone = findZoneByName(zones, specs[i].ZoneName)
+		} else {
+			zone = resolveZone(zones, specs[i].FQDN)
 		}
-		fmt.Println("获取公网IP成功:", currentIP)
-		comment := ""
-		// 获取当前 DNS 记录
-		dnsRecords, err := getDNSRecord(api, zoneID, fullDomain, comment)
-		if err != nil {
-			fmt.Println("获取 DNS 记录失败:", err)
-			continue
+		if zone == nil {
+			fmt.Printf("找不到与域名 %s 匹配的 Cloudflare Zone\n", specs[i].FQDN)
+			os.Exit(1)
 		}
-		dnsRecord := cloudflare.DNSRecord{}
-		if len(dnsRecords) == 0 {
-			dnsRecord, err := createDNSRecord(api, zoneID, subDomain, currentIP)
-			if err != nil {
-				fmt.Println("创建 DNS 记录失败:", err)
-				continue
+		specs[i].ZoneID = zone.ID
+		specs[i].ZoneName = zone.Name
+		specs[i].recordIDs = map[string]string{}
+		if isWildcard(specs[i].FQDN) {
+			for _, recordType := range specs[i].Types {
+				if specs[i].proxiedFor(recordType) {
+					fmt.Printf("泛域名记录 %s 无法在 Proxied 模式下使用：Cloudflare 仅 Enterprise 计划支持代理泛域名\n", specs[i].FQDN)
+					os.Exit(1)
+				}
 			}
-			fmt.Println("创建 DNS 记录成功:", dnsRecord.Name, currentIP)
-		} else {
-			dnsRecord = dnsRecords[0]
 		}
+		fmt.Println("域名:", specs[i].FQDN, "Zone:", zone.Name, "ZoneID:", zone.ID)
+	}
+
+	// 清理不再出现在当前配置中的历史托管记录
+	cleanupStaleRecords(api, zones, specs)
 
-		// 如果外网地址与 DNS 记录不一样，则更新 DNS 记录
-		if currentIP != dnsRecord.Content && dnsRecord.Content != "" {
-			fmt.Println("公网IP变化，更新DNS记录:", dnsRecord.Content+" => "+currentIP)
-			err := updateDNSRecord(api, dnsRecord.ID, zoneID, subDomain, currentIP)
-			if err != nil {
-				fmt.Println("更新 DNS 记录失败:", err)
-			} else {
-				fmt.Println("DNS 记录已更新:", dnsRecord.Name, currentIP)
+	runAll := func() {
+		for i := range specs {
+			for _, recordType := range specs[i].Types {
+				reconcile(api, ipProvider, &specs[i], recordType)
 			}
-		} else {
-			fmt.Println("公网地址与 DNS 记录一致，无需更新")
 		}
-		time.Sleep(time.Duration(period) * time.Second)
+	}
+
+	if once {
+		runAll()
+		return
+	}
+
+	// SCHEDULE 未设置时回退到按 PERIOD 秒数的固定间隔
+	schedule := scheduleEnv
+	if schedule == "" {
+		schedule = fmt.Sprintf("%ds", period)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := runScheduled(ctx, schedule, tz, runAll); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if deleteOnStop {
+		fmt.Println("DELETE_ON_STOP=true，正在删除所有托管记录...")
+		deleteManagedRecords(api, specs)
 	}
 }
 
-var lastSuccessfulAPI string
+// reconcile 对指定域名的指定记录类型（A/AAAA）执行一次完整的获取-对比-更新流程
+func reconcile(api *cloudflare.API, ipProvider IPProvider, spec *DomainSpec, recordType string) {
+	currentIP, err := ipProvider.GetIP(recordType)
+	if err != nil {
+		fmt.Printf("获取当前外网地址失败(%s): %s\n", recordType, err)
+		return
+	}
+	fmt.Printf("获取公网IP成功(%s, %s): %s\n", spec.FQDN, recordType, currentIP)
 
-// getCurrentIP 获取当前外网地址
-func getCurrentIP() (string, error) {
-	for _, api := range ipInfoAPIs {
-		if lastSuccessfulAPI != "" && api != lastSuccessfulAPI {
-			continue
-		}
-		resp, err := http.Get(api)
-		if err != nil {
-			// 记录错误日志
-			fmt.Printf("获取外网地址失败：%s\n", err)
-			continue
-		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
+	// 获取当前 DNS 记录
+	dnsRecords, err := getDNSRecord(api, spec.ZoneID, spec.FQDN, "", recordType)
+	if err != nil {
+		fmt.Printf("获取 DNS 记录失败(%s, %s): %s\n", spec.FQDN, recordType, err)
+		return
+	}
+	if len(dnsRecords) == 0 {
+		dnsRecord, err := createDNSRecord(api, spec.ZoneID, spec.FQDN, currentIP, recordType, spec.proxiedFor(recordType), spec.ttlFor(recordType), recordTags(spec.commentFor(recordType)))
 		if err != nil {
-			// 记录错误日志
-			fmt.Printf("读取响应体失败：%s\n", err)
-			continue
+			fmt.Printf("创建 DNS 记录失败(%s, %s): %s\n", spec.FQDN, recordType, err)
+			return
 		}
-		var ipInfo map[string]interface{}
-		err = json.Unmarshal(body, &ipInfo)
+		spec.recordIDs[recordType] = dnsRecord.ID
+		fmt.Println("创建 DNS 记录成功:", dnsRecord.Name, currentIP)
+		return
+	}
+	dnsRecord := dnsRecords[0]
+	spec.recordIDs[recordType] = dnsRecord.ID
+
+	// 如果外网地址与 DNS 记录不一样，则更新 DNS 记录
+	if currentIP != dnsRecord.Content && dnsRecord.Content != "" {
+		fmt.Println("公网IP变化，更新DNS记录:", dnsRecord.Content+" => "+currentIP)
+		err := updateDNSRecord(api, dnsRecord.ID, spec.ZoneID, spec.FQDN, currentIP, recordType, spec.proxiedFor(recordType), spec.ttlFor(recordType), recordTags(spec.commentFor(recordType)))
 		if err != nil {
-			// 记录错误日志
-			fmt.Printf("解析 JSON 失败：%s\n", err)
-			continue
-		}
-		// 如果成功获取到 IP 地址，返回它
-		if ip, ok := ipInfo["ip"].(string); ok {
-			lastSuccessfulAPI = api
-			return ip, nil
+			fmt.Printf("更新 DNS 记录失败(%s, %s): %s\n", spec.FQDN, recordType, err)
+		} else {
+			fmt.Println("DNS 记录已更新:", dnsRecord.Name, currentIP)
 		}
+	} else {
+		fmt.Printf("公网地址与 DNS 记录一致，无需更新(%s, %s)\n", spec.FQDN, recordType)
 	}
-	lastSuccessfulAPI = ""
-	// 如果所有 API 都失败，返回错误
-	return "", fmt.Errorf("所有 API 获取外网地址失败")
 }
 
 // getDNSRecord 获取指定的 Cloudflare DNS 记录
-func getDNSRecord(api *cloudflare.API, zoneID, name string, comment string) ([]cloudflare.DNSRecord, error) {
+// name 可以是泛域名（如 "*.dev.example.org"），cloudflare-go 在构造查询串时
+// 会对 "*" 做标准 URL 编码，Cloudflare 返回的记录 Name 仍是未编码的 "*.xxx" 原文
+func getDNSRecord(api *cloudflare.API, zoneID, name string, comment string, recordType string) ([]cloudflare.DNSRecord, error) {
 
 	// 定义 ListDNSRecordsParams 参数
 	params := cloudflare.ListDNSRecordsParams{
 		Name:    name,
-		Type:    "A",
+		Type:    recordType,
 		Comment: comment,
 	}
 
@@ -184,13 +372,18 @@ func getDNSRecord(api *cloudflare.API, zoneID, name string, comment string) ([]c
 }
 
 // 新建DNS 记录
-func createDNSRecord(api *cloudflare.API, zoneID, subdomain, content string) (cloudflare.DNSRecord, error) {
+func createDNSRecord(api *cloudflare.API, zoneID, name, content string, recordType string, proxied bool, ttl int, tags []string) (cloudflare.DNSRecord, error) {
+	if isWildcard(name) && proxied {
+		return cloudflare.DNSRecord{}, fmt.Errorf("泛域名记录 %s 无法在 Proxied 模式下创建：Cloudflare 仅 Enterprise 计划支持代理泛域名", name)
+	}
 	createdRecord := cloudflare.CreateDNSRecordParams{
-		Name:    subdomain,
+		Name:    name,
 		Content: content,
-		Type:    "A",
-		Proxied: &[]bool{false}[0],
-		ZoneID:  zoneID,
+		Type:    recordType,
+		Proxied: &proxied,
+		TTL:     ttl,
+		Comment: managedComment(),
+		Tags:    tags,
 	}
 
 	rc := &cloudflare.ResourceContainer{Identifier: zoneID}
@@ -201,13 +394,18 @@ func createDNSRecord(api *cloudflare.API, zoneID, subdomain, content string) (cl
 }
 
 // updateDNSRecord 更新指定的 Cloudflare DNS 记录
-func updateDNSRecord(api *cloudflare.API, recordID, zoneID, subdomain, content string) error {
+func updateDNSRecord(api *cloudflare.API, recordID, zoneID, name, content string, recordType string, proxied bool, ttl int, tags []string) error {
+	comment := managedComment()
 	// 定义更新的 DNS 记录
 	updatedRecord := cloudflare.UpdateDNSRecordParams{
 		ID:      recordID,
-		Name:    subdomain,
+		Name:    name,
 		Content: content,
-		Type:    "A", // 假设类型是 A 记录，根据实际情况修改
+		Type:    recordType,
+		Proxied: &proxied,
+		TTL:     ttl,
+		Comment: &comment,
+		Tags:    tags,
 	}
 
 	// 定义 ResourceContainer
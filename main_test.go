@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+func TestResolveZone(t *testing.T) {
+	zones := []cloudflare.Zone{
+		{ID: "z-example", Name: "example.com"},
+		{ID: "z-sub", Name: "dev.example.com"},
+		{ID: "z-couk", Name: "example.co.uk"},
+	}
+
+	cases := []struct {
+		fqdn   string
+		wantID string
+	}{
+		{"example.com", "z-example"},
+		{"www.example.com", "z-example"},
+		// 多级 zone 同时匹配时应选择名称最长（最具体）的那个
+		{"foo.dev.example.com", "z-sub"},
+		{"dev.example.com", "z-sub"},
+		{"www.example.co.uk", "z-couk"},
+		{"unrelated.org", ""},
+	}
+
+	for _, c := range cases {
+		got := resolveZone(zones, c.fqdn)
+		if c.wantID == "" {
+			if got != nil {
+				t.Errorf("resolveZone(%q) = %v, want nil", c.fqdn, got.ID)
+			}
+			continue
+		}
+		if got == nil || got.ID != c.wantID {
+			t.Errorf("resolveZone(%q) = %v, want %s", c.fqdn, got, c.wantID)
+		}
+	}
+}
+
+func TestIsWildcard(t *testing.T) {
+	cases := map[string]bool{
+		"*.example.com":   true,
+		"www.example.com": false,
+		"*":               false,
+		"example.com":     false,
+	}
+	for fqdn, want := range cases {
+		if got := isWildcard(fqdn); got != want {
+			t.Errorf("isWildcard(%q) = %v, want %v", fqdn, got, want)
+		}
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	origProxied, origTTL := defaultProxied, defaultTTL
+	defaultProxied, defaultTTL = false, 1
+	defer func() { defaultProxied, defaultTTL = origProxied, origTTL }()
+
+	specs, err := parseDomains("a.example.com|proxied=true|ttl=120, b.example.com")
+	if err != nil {
+		t.Fatalf("parseDomains() error = %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("parseDomains() returned %d specs, want 2", len(specs))
+	}
+	if specs[0].FQDN != "a.example.com" || !specs[0].Proxied || specs[0].TTL != 120 {
+		t.Errorf("specs[0] = %+v, want proxied=true ttl=120", specs[0])
+	}
+	if specs[1].FQDN != "b.example.com" || specs[1].Proxied != defaultProxied || specs[1].TTL != defaultTTL {
+		t.Errorf("specs[1] = %+v, want defaults", specs[1])
+	}
+
+	if _, err := parseDomains("a.example.com|proxied=ture"); err == nil {
+		t.Error("parseDomains() with invalid proxied value should error, got nil")
+	}
+	if _, err := parseDomains("a.example.com|ttl=abc"); err == nil {
+		t.Error("parseDomains() with invalid ttl value should error, got nil")
+	}
+	if _, err := parseDomains(""); err == nil {
+		t.Error("parseDomains(\"\") should error, got nil")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// runScheduled 按 schedule 驱动 fn 的周期执行，直到 ctx 被取消（收到 SIGINT/SIGTERM）
+// schedule 既可以是 time.ParseDuration 支持的固定间隔（如 "30s"、"5m"），
+// 也可以是标准 cron 表达式——5 段（分 时 日 月 周，crontab/Kubernetes CronJob 的写法）
+// 或 6 段（额外带秒）均可，按字段数自动选择解析方式；
+// 此时 tz 用于指定表达式的解释时区，留空默认本地时区
+func runScheduled(ctx context.Context, schedule string, tz string, fn func()) error {
+	if d, err := time.ParseDuration(schedule); err == nil {
+		runOnInterval(ctx, d, fn)
+		return nil
+	}
+
+	loc := time.Local
+	if tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return fmt.Errorf("解析 TZ=%s 失败: %w", tz, err)
+		}
+		loc = l
+	}
+
+	// 显式指定 SkipIfStillRunning：cron 默认的任务链不会同步多次触发，
+	// 若上一次 fn 还没跑完（reconcile 对账号下多个域名/zone 逐个请求 Cloudflare API，
+	// 遇到秒级 cron 表达式或较慢的网络时很容易超过下一次触发时间），
+	// 下一次触发会在新的 goroutine 里并发调用 fn，而 fn 内部会并发写同一个
+	// spec.recordIDs，引发 "fatal error: concurrent map writes" 直接崩溃进程；
+	// 跳过仍在运行中的这一次可以保证同一时刻只有一个 fn 在执行
+	opts := []cron.Option{
+		cron.WithLocation(loc),
+		cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)),
+	}
+	if len(strings.Fields(schedule)) == 6 {
+		opts = append(opts, cron.WithSeconds())
+	}
+	c := cron.New(opts...)
+	if _, err := c.AddFunc(schedule, fn); err != nil {
+		return fmt.Errorf("解析 SCHEDULE=%s 失败: %w", schedule, err)
+	}
+	fn() // 启动时先执行一次，与固定间隔模式的行为保持一致
+	c.Start()
+	<-ctx.Done()
+	fmt.Println("收到退出信号，正在停止调度器...")
+	<-c.Stop().Done()
+	return nil
+}
+
+// runOnInterval 以固定间隔重复调用 fn，直到 ctx 被取消
+func runOnInterval(ctx context.Context, d time.Duration, fn func()) {
+	fn()
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("收到退出信号，正在停止...")
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunScheduledFixedInterval(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithTimeout(context.Background(), 220*time.Millisecond)
+	defer cancel()
+
+	if err := runScheduled(ctx, "50ms", "", func() { atomic.AddInt32(&count, 1) }); err != nil {
+		t.Fatalf("runScheduled() error = %v", err)
+	}
+	// 启动时立即执行一次，之后约每 50ms 一次，220ms 内至少应再执行 2 次以上
+	if got := atomic.LoadInt32(&count); got < 3 {
+		t.Errorf("fn called %d times, want at least 3", got)
+	}
+}
+
+func TestRunScheduledSixFieldCronUsesSeconds(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithTimeout(context.Background(), 1300*time.Millisecond)
+	defer cancel()
+
+	// 6 段表达式（带秒）若被错误地当成 5 段（分钟级）解析，要么直接 AddFunc 报错，
+	// 要么要等到下一个整分钟才会触发，1.3s 内不可能观察到第二次调用
+	err := runScheduled(ctx, "* * * * * *", "", func() { atomic.AddInt32(&count, 1) })
+	if err != nil {
+		t.Fatalf("runScheduled() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got < 2 {
+		t.Errorf("fn called %d times within ~1.3s of a per-second cron, want at least 2", got)
+	}
+}
+
+func TestRunScheduledFiveFieldCronAccepted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var count int32
+	// 标准 5 段表达式不应该要求 WithSeconds，也不应该在 AddFunc 时报错
+	err := runScheduled(ctx, "0 0 1 1 *", "", func() { atomic.AddInt32(&count, 1) })
+	if err != nil {
+		t.Fatalf("runScheduled() error = %v, want 5-field cron accepted", err)
+	}
+	// 启动时的立即执行应当发生，但 1 月 1 日 0 点的下一次触发不会在 100ms 内到来
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("fn called %d times, want exactly 1 (startup run only)", got)
+	}
+}
+
+func TestRunScheduledInvalidCronExpression(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := runScheduled(ctx, "not a valid cron expr !!", "", func() {}); err == nil {
+		t.Error("runScheduled() with an invalid cron expression should error, got nil")
+	}
+}
+
+func TestRunScheduledInvalidTZ(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := runScheduled(ctx, "* * * * *", "Not/AZone", func() {}); err == nil {
+		t.Error("runScheduled() with an invalid TZ should error, got nil")
+	}
+}
+
+func TestRunScheduledSkipsOverlappingFiring(t *testing.T) {
+	var running int32
+	var overlapped int32
+	fn := func() {
+		if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+			atomic.StoreInt32(&overlapped, 1)
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
+		atomic.StoreInt32(&running, 0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 650*time.Millisecond)
+	defer cancel()
+
+	// 每秒触发一次的 fn 需要 250ms 才能跑完，SkipIfStillRunning 应当跳过
+	// 仍在执行中的那几次触发，而不是让它们在新 goroutine 里并发调用 fn
+	if err := runScheduled(ctx, "* * * * * *", "", fn); err != nil {
+		t.Fatalf("runScheduled() error = %v", err)
+	}
+	if atomic.LoadInt32(&overlapped) == 1 {
+		t.Error("fn was entered concurrently; SkipIfStillRunning should have prevented this")
+	}
+}